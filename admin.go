@@ -0,0 +1,322 @@
+package courier
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const defaultAdminPageSize = 100
+const maxAdminPageSize = 500
+
+// adminCursor is the keyset position encoded into the opaque "cursor"
+// query param. Paging is keyed on (created_on, id) rather than offset,
+// so a deep page costs the same as the first one instead of scanning
+// and discarding everything before it.
+type adminCursor struct {
+	CreatedOn time.Time `json:"created_on"`
+	ID        int64     `json:"id"`
+}
+
+func encodeCursor(c adminCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*adminCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %s", err)
+	}
+	c := &adminCursor{}
+	if err := json.Unmarshal(raw, c); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %s", err)
+	}
+	return c, nil
+}
+
+// MessageFilter narrows an admin message listing.
+type MessageFilter struct {
+	ID          int64
+	ChannelUUID ChannelUUID
+	Status      MsgStatusValue
+	URN         string
+	After       time.Time
+	Before      time.Time
+}
+
+// AdminStore is implemented by backends that support the admin browsing
+// API. It is kept separate from Backend so backends that haven't
+// migrated their schema for it yet aren't forced to implement it.
+//
+// SelectMessages returns up to limit rows for the given filter,
+// positioned relative to cursor. When forward is true, rows are
+// returned in ascending (created_on, id) order starting just after
+// cursor (or from the beginning, if cursor is nil) - the normal "next
+// page" direction. When forward is false, rows are returned in
+// descending (created_on, id) order starting just before cursor, i.e.
+// the limit rows immediately preceding it; ListMessagesHandler is
+// responsible for reversing that slice back to ascending order before
+// it's presented as a page.
+type AdminStore interface {
+	SelectMessages(ctx context.Context, filter MessageFilter, cursor *adminCursor, limit int, forward bool) ([]Msg, error)
+	SelectDispatchAttempts(ctx context.Context, msgID int64) ([]DispatchAttempt, error)
+	RequeueMessage(ctx context.Context, msgID int64) error
+}
+
+// keysetRow is the minimal surface paginate needs from a row, so the
+// keyset math can be unit tested without a full Msg implementation.
+type keysetRow interface {
+	CreatedOn() time.Time
+	ID() MsgID
+}
+
+// paginate trims a (limit+1)-row fetch down to a page of at most limit
+// rows and computes the next/prev cursors, given the direction that was
+// queried. rows must already be in the order SelectMessages promises for
+// that direction (ascending for forward, descending for backward); on
+// return, page is always in ascending (created_on, id) order.
+func paginate(rows []keysetRow, limit int, forward bool, haveCursor bool) (page []keysetRow, next, prev string) {
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	if !forward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	if len(rows) == 0 {
+		return rows, "", ""
+	}
+
+	first, last := rows[0], rows[len(rows)-1]
+	if forward {
+		if hasMore {
+			next = encodeCursor(adminCursor{CreatedOn: last.CreatedOn(), ID: last.ID().Int64})
+		}
+		if haveCursor {
+			prev = encodeCursor(adminCursor{CreatedOn: first.CreatedOn(), ID: first.ID().Int64})
+		}
+	} else {
+		// we paged backward from haveCursor, so there's always a way
+		// forward again, and a further prev page only if we found more
+		// than a page's worth of rows before this one.
+		next = encodeCursor(adminCursor{CreatedOn: last.CreatedOn(), ID: last.ID().Int64})
+		if hasMore {
+			prev = encodeCursor(adminCursor{CreatedOn: first.CreatedOn(), ID: first.ID().Int64})
+		}
+	}
+
+	return rows, next, prev
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, msg string) {
+	writeAdminJSON(w, status, map[string]string{"error": msg})
+}
+
+// cursorURL builds the URL a client should follow to fetch the page in
+// the given direction, explicitly setting "dir" for that direction
+// rather than cloning whatever "dir" happened to be on the current
+// request - otherwise a next/prev link generated from a backward page
+// (dir=prev) would inherit dir=prev and page backward again instead of
+// forward, and vice versa.
+func cursorURL(r *http.Request, cursor string, dir string) string {
+	q := r.URL.Query()
+	q.Set("cursor", cursor)
+	if dir == "" {
+		q.Del("dir")
+	} else {
+		q.Set("dir", dir)
+	}
+	u := *r.URL
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func writeLinkHeader(w http.ResponseWriter, r *http.Request, next, prev string) {
+	links := make([]string, 0, 2)
+	if next != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, cursorURL(r, next, "")))
+	}
+	if prev != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, cursorURL(r, prev, "prev")))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", joinLinks(links))
+	}
+}
+
+func joinLinks(links []string) string {
+	joined := links[0]
+	for _, l := range links[1:] {
+		joined += ", " + l
+	}
+	return joined
+}
+
+// ListMessagesHandler serves GET /admin/messages and GET
+// /admin/channels/{uuid}/messages, returning a keyset page of messages
+// matching the given filters.
+func ListMessagesHandler(store AdminStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		q := r.URL.Query()
+
+		filter := MessageFilter{}
+		if uuid := mux.Vars(r)["uuid"]; uuid != "" {
+			filter.ChannelUUID = NewChannelUUID(uuid)
+		} else if uuid := q.Get("channel"); uuid != "" {
+			filter.ChannelUUID = NewChannelUUID(uuid)
+		}
+		if urn := q.Get("urn"); urn != "" {
+			filter.URN = urn
+		}
+		if status := q.Get("status"); status != "" {
+			filter.Status = MsgStatusValue(status)
+		}
+		if after := q.Get("after"); after != "" {
+			t, err := time.Parse(time.RFC3339, after)
+			if err != nil {
+				writeAdminError(w, http.StatusBadRequest, "invalid after")
+				return
+			}
+			filter.After = t
+		}
+		if before := q.Get("before"); before != "" {
+			t, err := time.Parse(time.RFC3339, before)
+			if err != nil {
+				writeAdminError(w, http.StatusBadRequest, "invalid before")
+				return
+			}
+			filter.Before = t
+		}
+
+		limit := defaultAdminPageSize
+		if raw := q.Get("limit"); raw != "" {
+			if n, err := strconv.Atoi(raw); err == nil && n > 0 && n <= maxAdminPageSize {
+				limit = n
+			}
+		}
+
+		cursor, err := decodeCursor(q.Get("cursor"))
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		forward := q.Get("dir") != "prev"
+
+		// fetch one extra row so we know whether there's another page
+		// beyond this one, in whichever direction we're paging
+		msgs, err := store.SelectMessages(ctx, filter, cursor, limit+1, forward)
+		if err != nil {
+			writeAdminError(w, http.StatusInternalServerError, "error fetching messages")
+			return
+		}
+
+		rows := make([]keysetRow, len(msgs))
+		for i, m := range msgs {
+			rows[i] = m
+		}
+
+		rows, next, prev := paginate(rows, limit, forward, cursor != nil)
+
+		msgs = make([]Msg, len(rows))
+		for i, row := range rows {
+			msgs[i] = row.(Msg)
+		}
+
+		writeLinkHeader(w, r, next, prev)
+
+		writeAdminJSON(w, http.StatusOK, msgs)
+	}
+}
+
+// GetMessageHandler serves GET /admin/messages/{id}, returning a single
+// message along with its channel log.
+func GetMessageHandler(store AdminStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid message id")
+			return
+		}
+
+		msgs, err := store.SelectMessages(r.Context(), MessageFilter{ID: id}, nil, 1, true)
+		if err != nil || len(msgs) == 0 {
+			writeAdminError(w, http.StatusNotFound, "message not found")
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, msgs[0])
+	}
+}
+
+// GetDispatchesHandler serves GET /admin/messages/{id}/dispatches,
+// returning the full per-attempt dispatch history recorded for that
+// message by sendMsgWithRetry.
+func GetDispatchesHandler(store AdminStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid message id")
+			return
+		}
+
+		attempts, err := store.SelectDispatchAttempts(r.Context(), id)
+		if err != nil {
+			writeAdminError(w, http.StatusInternalServerError, "error fetching dispatch attempts")
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, attempts)
+	}
+}
+
+// RequeueMessageHandler serves POST /admin/messages/{id}/requeue,
+// resetting a failed message back onto the send queue.
+func RequeueMessageHandler(store AdminStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid message id")
+			return
+		}
+
+		if err := store.RequeueMessage(r.Context(), id); err != nil {
+			writeAdminError(w, http.StatusInternalServerError, "error requeuing message")
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]string{"status": "queued"})
+	}
+}
+
+// RegisterAdminRoutes mounts the admin browsing API under the given
+// router, wrapping every route in the supplied authentication
+// middleware. It is called once from Server.Start, alongside the per
+// channel handler routes each handler registers in its Initialize.
+func RegisterAdminRoutes(router *mux.Router, store AdminStore, authenticate func(http.Handler) http.Handler) {
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(authenticate)
+
+	admin.HandleFunc("/messages", ListMessagesHandler(store)).Methods(http.MethodGet)
+	admin.HandleFunc("/messages/{id}", GetMessageHandler(store)).Methods(http.MethodGet)
+	admin.HandleFunc("/messages/{id}/dispatches", GetDispatchesHandler(store)).Methods(http.MethodGet)
+	admin.HandleFunc("/messages/{id}/requeue", RequeueMessageHandler(store)).Methods(http.MethodPost)
+	admin.HandleFunc("/channels/{uuid}/messages", ListMessagesHandler(store)).Methods(http.MethodGet)
+}
@@ -0,0 +1,182 @@
+package courier
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeRow struct {
+	createdOn time.Time
+	id        int64
+}
+
+func (f fakeRow) CreatedOn() time.Time { return f.createdOn }
+func (f fakeRow) ID() MsgID            { return MsgID{Int64: f.id} }
+
+func makeRows(ids ...int64) []keysetRow {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := make([]keysetRow, len(ids))
+	for i, id := range ids {
+		rows[i] = fakeRow{createdOn: base.Add(time.Duration(id) * time.Minute), id: id}
+	}
+	return rows
+}
+
+func TestPaginateForward(t *testing.T) {
+	// 6 rows fetched for a page size of 5: one extra row signals more to come
+	rows := makeRows(1, 2, 3, 4, 5, 6)
+
+	page, next, prev := paginate(rows, 5, true, false)
+	if len(page) != 5 {
+		t.Fatalf("expected page of 5, got %d", len(page))
+	}
+	if page[0].ID().Int64 != 1 || page[len(page)-1].ID().Int64 != 5 {
+		t.Fatalf("expected ascending page [1..5], got first=%d last=%d", page[0].ID().Int64, page[len(page)-1].ID().Int64)
+	}
+	if next == "" {
+		t.Errorf("expected a next cursor since there was an extra row")
+	}
+	if prev != "" {
+		t.Errorf("expected no prev cursor on the first page, got %q", prev)
+	}
+
+	// a subsequent forward page, now with a cursor behind us
+	page, next, prev = paginate(rows, 5, true, true)
+	if prev == "" {
+		t.Errorf("expected a prev cursor once we're paging from a cursor")
+	}
+	_ = next
+}
+
+func TestPaginateBackward(t *testing.T) {
+	// SelectMessages(forward=false) returns rows in descending order,
+	// newest (closest to the cursor) first
+	rows := makeRows(6, 5, 4, 3, 2)
+
+	page, next, prev := paginate(rows, 5, false, true)
+	if len(page) != 5 {
+		t.Fatalf("expected page of 5, got %d", len(page))
+	}
+	// the page must come back out in ascending order regardless of the
+	// descending order it was fetched in
+	if page[0].ID().Int64 != 2 || page[len(page)-1].ID().Int64 != 6 {
+		t.Fatalf("expected ascending page [2..6], got first=%d last=%d", page[0].ID().Int64, page[len(page)-1].ID().Int64)
+	}
+	if next == "" {
+		t.Errorf("expected a next cursor pointing back toward where we came from")
+	}
+	if prev != "" {
+		t.Errorf("expected no prev cursor since there was no extra (older) row")
+	}
+
+	// with an extra (older) row fetched, there should be a prev cursor too
+	rowsWithMore := makeRows(6, 5, 4, 3, 2, 1)
+	page, next, prev = paginate(rowsWithMore, 5, false, true)
+	if prev == "" {
+		t.Errorf("expected a prev cursor since an extra older row was fetched")
+	}
+	if page[0].ID().Int64 != 2 || page[len(page)-1].ID().Int64 != 6 {
+		t.Fatalf("expected ascending page [2..6], got first=%d last=%d", page[0].ID().Int64, page[len(page)-1].ID().Int64)
+	}
+	_ = next
+}
+
+func TestWriteLinkHeaderDirection(t *testing.T) {
+	// a forward page (no dir on the request) must produce a prev link
+	// that pages backward (dir=prev), not another forward query
+	r := httptest.NewRequest("GET", "http://example.com/admin/messages?cursor=10&limit=5", nil)
+	w := httptest.NewRecorder()
+	writeLinkHeader(w, r, "next-cursor", "prev-cursor")
+
+	next, prev := parseLinkHeader(t, w.Header().Get("Link"))
+
+	nextQ, err := url.ParseQuery(next)
+	if err != nil {
+		t.Fatalf("invalid next URL query: %s", err)
+	}
+	if nextQ.Get("dir") != "" {
+		t.Errorf("expected next link to have no dir param, got dir=%q", nextQ.Get("dir"))
+	}
+	if nextQ.Get("cursor") != "next-cursor" {
+		t.Errorf("expected next link cursor=next-cursor, got %q", nextQ.Get("cursor"))
+	}
+
+	prevQ, err := url.ParseQuery(prev)
+	if err != nil {
+		t.Fatalf("invalid prev URL query: %s", err)
+	}
+	if prevQ.Get("dir") != "prev" {
+		t.Errorf("expected prev link to have dir=prev, got dir=%q", prevQ.Get("dir"))
+	}
+	if prevQ.Get("cursor") != "prev-cursor" {
+		t.Errorf("expected prev link cursor=prev-cursor, got %q", prevQ.Get("cursor"))
+	}
+
+	// a backward page (dir=prev on the request) must produce a next
+	// link that pages forward (no dir), not another backward query
+	r2 := httptest.NewRequest("GET", "http://example.com/admin/messages?cursor=10&dir=prev&limit=5", nil)
+	w2 := httptest.NewRecorder()
+	writeLinkHeader(w2, r2, "next-cursor", "prev-cursor")
+
+	next2, prev2 := parseLinkHeader(t, w2.Header().Get("Link"))
+
+	next2Q, err := url.ParseQuery(next2)
+	if err != nil {
+		t.Fatalf("invalid next URL query: %s", err)
+	}
+	if next2Q.Get("dir") != "" {
+		t.Errorf("expected next link from a backward page to clear dir, got dir=%q", next2Q.Get("dir"))
+	}
+
+	prev2Q, err := url.ParseQuery(prev2)
+	if err != nil {
+		t.Fatalf("invalid prev URL query: %s", err)
+	}
+	if prev2Q.Get("dir") != "prev" {
+		t.Errorf("expected prev link from a backward page to keep dir=prev, got dir=%q", prev2Q.Get("dir"))
+	}
+}
+
+// parseLinkHeader extracts the next/prev URLs' query strings from a Link
+// header built by writeLinkHeader, e.g. `<url1>; rel="next", <url2>; rel="prev"`.
+func parseLinkHeader(t *testing.T, header string) (nextQuery, prevQuery string) {
+	t.Helper()
+	for _, part := range strings.Split(header, ", ") {
+		lt, gt := strings.IndexByte(part, '<'), strings.IndexByte(part, '>')
+		if lt < 0 || gt < 0 || gt < lt {
+			t.Fatalf("malformed Link header segment: %q", part)
+		}
+		u, err := url.Parse(part[lt+1 : gt])
+		if err != nil {
+			t.Fatalf("invalid URL in Link header: %s", err)
+		}
+
+		switch {
+		case strings.Contains(part, `rel="next"`):
+			nextQuery = u.RawQuery
+		case strings.Contains(part, `rel="prev"`):
+			prevQuery = u.RawQuery
+		}
+	}
+	return nextQuery, prevQuery
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := adminCursor{CreatedOn: time.Date(2026, 7, 27, 12, 0, 0, 0, time.UTC), ID: 42}
+	encoded := encodeCursor(c)
+
+	decoded, err := decodeCursor(encoded)
+	if err != nil {
+		t.Fatalf("unexpected error decoding cursor: %s", err)
+	}
+	if decoded.ID != c.ID || !decoded.CreatedOn.Equal(c.CreatedOn) {
+		t.Errorf("round-tripped cursor %+v, want %+v", decoded, c)
+	}
+
+	if _, err := decodeCursor("not-valid-base64!!"); err == nil {
+		t.Errorf("expected an error decoding an invalid cursor")
+	}
+}
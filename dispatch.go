@@ -0,0 +1,29 @@
+package courier
+
+import "time"
+
+// DispatchAttempt records the outcome of a single attempt to deliver a
+// message to a channel's upstream API. A MsgStatus accumulates one of
+// these per try, so operators can see exactly why a delivery took
+// several attempts (or never succeeded) instead of only the final
+// channel log. The raw HTTP status code and response body for an
+// attempt are already captured in the ChannelLog the handler attaches
+// via status.AddLog; they aren't duplicated here since sendMsgWithRetry
+// only sees the handler's (MsgStatus, error) return, not its HTTP
+// response.
+type DispatchAttempt struct {
+	Attempt    int            `json:"attempt"`
+	StartedOn  time.Time      `json:"started_on"`
+	FinishedOn time.Time      `json:"finished_on"`
+	Error      string         `json:"error,omitempty"`
+	Status     MsgStatusValue `json:"status"`
+}
+
+// DispatchLogger is implemented by MsgStatus values whose backend
+// supports recording per-attempt dispatch history, so WriteMsgStatus can
+// persist the full attempt/backoff timeline alongside the final status.
+// It is kept separate from MsgStatus so backends that haven't added the
+// supporting table yet aren't forced to implement it.
+type DispatchLogger interface {
+	AddDispatchAttempt(a DispatchAttempt)
+}
@@ -42,6 +42,10 @@ func (h *handler) Initialize(s courier.Server) error {
 
 // ReceiveMessage is our HTTP handler function for incoming messages
 func (h *handler) ReceiveMessage(ctx context.Context, channel courier.Channel, w http.ResponseWriter, r *http.Request) ([]courier.Event, error) {
+	if err := handlers.VerifySignedWebhook(channel, r); err != nil {
+		return nil, err
+	}
+
 	// get our params
 	bmMsg := &bmMessage{}
 	err := handlers.DecodeAndValidateForm(bmMsg, r)
@@ -79,6 +83,10 @@ var bmStatusMapping = map[int]courier.MsgStatusValue{
 
 // StatusMessage is our HTTP handler function for status updates
 func (h *handler) StatusMessage(ctx context.Context, channel courier.Channel, w http.ResponseWriter, r *http.Request) ([]courier.Event, error) {
+	if err := handlers.VerifySignedWebhook(channel, r); err != nil {
+		return nil, err
+	}
+
 	// get our params
 	bmStatus := &bmStatus{}
 	err := handlers.DecodeAndValidateForm(bmStatus, r)
@@ -134,6 +142,12 @@ func (h *handler) SendMsg(ctx context.Context, msg courier.Msg) (courier.MsgStat
 	status := h.Backend().NewMsgStatusForID(msg.Channel(), msg.ID(), courier.MsgErrored)
 	status.AddLog(courier.NewChannelLogFromRR("Message Sent", msg.Channel(), msg.ID(), rr).WithError("Message Send Error", err))
 	if err != nil {
+		// a 4xx means our credentials or request are bad and retrying
+		// won't help; anything else (network error, 5xx, 429) is left as
+		// MsgErrored so the engine's retry policy gives it another try
+		if rr != nil && rr.StatusCode >= 400 && rr.StatusCode < 500 && rr.StatusCode != http.StatusTooManyRequests {
+			status.SetStatus(courier.MsgFailed)
+		}
 		return status, nil
 	}
 
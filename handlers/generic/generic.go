@@ -0,0 +1,391 @@
+package generic
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/buger/jsonparser"
+	"github.com/nyaruka/courier"
+	"github.com/nyaruka/courier/handlers"
+	"github.com/nyaruka/courier/utils"
+	"github.com/nyaruka/gocommon/urns"
+	"github.com/pkg/errors"
+)
+
+// configRequestSpec is the channel config key under which operators
+// store the declarative description of how to talk to their SMS
+// gateway over HTTP, so long-tail providers can be onboarded without a
+// dedicated handler package.
+const configRequestSpec = "request_spec"
+
+type handler struct {
+	handlers.BaseHandler
+}
+
+func newHandler() courier.ChannelHandler {
+	return &handler{handlers.NewBaseHandler(courier.ChannelType("GEN"), "Generic HTTP")}
+}
+
+func init() {
+	courier.RegisterHandler(newHandler())
+}
+
+// Initialize is called by the engine once everything is loaded
+func (h *handler) Initialize(s courier.Server) error {
+	h.SetServer(s)
+	err := s.AddHandlerRoute(h, http.MethodPost, "receive", h.ReceiveMessage)
+	if err != nil {
+		return err
+	}
+	err = s.AddHandlerRoute(h, http.MethodGet, "receive", h.ReceiveMessage)
+	if err != nil {
+		return err
+	}
+	return s.AddHandlerRoute(h, http.MethodPost, "status", h.StatusMessage)
+}
+
+// requestSpec describes how to send, receive and check delivery status
+// for a single SMS provider entirely from channel config, instead of
+// compiling in a new handler per gateway.
+type requestSpec struct {
+	Send    sendSpec    `json:"send"`
+	Receive receiveSpec `json:"receive"`
+	Status  statusSpec  `json:"status"`
+}
+
+type authSpec struct {
+	Type     string `json:"type"` // "basic", "bearer" or "hmac", defaults to none
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+	Secret   string `json:"secret"`
+	Header   string `json:"header"` // hmac signature header, defaults to X-Signature
+}
+
+type sendSpec struct {
+	Method      string            `json:"method"`   // defaults to POST
+	URL         string            `json:"url"`      // go template, given Msg/Channel/Text
+	Headers     map[string]string `json:"headers"`  // go templates
+	Body        string            `json:"body"`     // go template
+	Encoding    string            `json:"encoding"` // "form" or "json", defaults to "form"
+	Auth        authSpec          `json:"auth"`
+	IDPath      []string          `json:"id_path"`      // jsonparser path to the external id in the response
+	IDRegex     string            `json:"id_regex"`     // used instead of id_path for non-JSON responses
+	StatusCodes map[string]string `json:"status_codes"` // HTTP status code -> status name, e.g. "429": "errored"
+}
+
+type receiveSpec struct {
+	ToField   string `json:"to_field"`
+	FromField string `json:"from_field"`
+	TextField string `json:"text_field"`
+}
+
+type statusSpec struct {
+	IDField     string            `json:"id_field"`
+	StatusField string            `json:"status_field"`
+	StatusMap   map[string]string `json:"status_map"` // raw status value -> status name
+}
+
+var statusValuesByName = map[string]courier.MsgStatusValue{
+	"wired":     courier.MsgWired,
+	"sent":      courier.MsgSent,
+	"delivered": courier.MsgDelivered,
+	"errored":   courier.MsgErrored,
+	"failed":    courier.MsgFailed,
+}
+
+func statusValueForName(name string) (courier.MsgStatusValue, error) {
+	v, found := statusValuesByName[strings.ToLower(name)]
+	if !found {
+		return courier.MsgFailed, fmt.Errorf("unknown status value '%s'", name)
+	}
+	return v, nil
+}
+
+func (h *handler) getRequestSpec(channel courier.Channel) (*requestSpec, error) {
+	raw := channel.StringConfigForKey(configRequestSpec, "")
+	if raw == "" {
+		return nil, fmt.Errorf("no request_spec set for generic channel")
+	}
+
+	spec := &requestSpec{}
+	if err := json.Unmarshal([]byte(raw), spec); err != nil {
+		return nil, errors.Wrap(err, "invalid request_spec")
+	}
+	return spec, nil
+}
+
+// resolveFieldName returns the configured field name, falling back to
+// the handler's default when the channel hasn't overridden it.
+func resolveFieldName(field, fallback string) string {
+	if field == "" {
+		return fallback
+	}
+	return field
+}
+
+func formValue(r *http.Request, field, fallback string) string {
+	return r.Form.Get(resolveFieldName(field, fallback))
+}
+
+// ReceiveMessage is our HTTP handler function for incoming messages
+func (h *handler) ReceiveMessage(ctx context.Context, channel courier.Channel, w http.ResponseWriter, r *http.Request) ([]courier.Event, error) {
+	if err := handlers.VerifySignedWebhook(channel, r); err != nil {
+		return nil, err
+	}
+
+	spec, err := h.getRequestSpec(channel)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	fromField := resolveFieldName(spec.Receive.FromField, "from")
+	from := r.Form.Get(fromField)
+	if from == "" {
+		return nil, fmt.Errorf("missing required field '%s'", fromField)
+	}
+	text := formValue(r, spec.Receive.TextField, "text")
+
+	urn := urns.NewTelURNForCountry(from, channel.Country())
+	msg := h.Backend().NewIncomingMsg(channel, urn, text)
+
+	if err := h.Backend().WriteMsg(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	return []courier.Event{msg}, courier.WriteMsgSuccess(ctx, w, r, []courier.Msg{msg})
+}
+
+// StatusMessage is our HTTP handler function for status updates
+func (h *handler) StatusMessage(ctx context.Context, channel courier.Channel, w http.ResponseWriter, r *http.Request) ([]courier.Event, error) {
+	if err := handlers.VerifySignedWebhook(channel, r); err != nil {
+		return nil, err
+	}
+
+	spec, err := h.getRequestSpec(channel)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	id := formValue(r, spec.Status.IDField, "id")
+	rawStatus := formValue(r, spec.Status.StatusField, "status")
+	if id == "" || rawStatus == "" {
+		return nil, fmt.Errorf("missing id or status field")
+	}
+
+	name, found := spec.Status.StatusMap[rawStatus]
+	if !found {
+		return nil, fmt.Errorf("unknown status value '%s', not found in status_map", rawStatus)
+	}
+	msgStatus, err := statusValueForName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	status := h.Backend().NewMsgStatusForExternalID(channel, id, msgStatus)
+	if err := h.Backend().WriteMsgStatus(ctx, status); err != nil {
+		return nil, err
+	}
+
+	return []courier.Event{status}, courier.WriteStatusSuccess(ctx, w, r, []courier.MsgStatus{status})
+}
+
+type templateData struct {
+	Msg     courier.Msg
+	Channel courier.Channel
+	Text    string
+}
+
+func renderTemplate(name, tpl string, data templateData) (string, error) {
+	t, err := template.New(name).Parse(tpl)
+	if err != nil {
+		return "", err
+	}
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func buildBody(spec sendSpec, data templateData) (string, string, error) {
+	body, err := renderTemplate("body", spec.Body, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	encoding := spec.Encoding
+	if encoding == "" {
+		encoding = "form"
+	}
+
+	switch encoding {
+	case "json":
+		return body, "application/json", nil
+	case "form":
+		return body, "application/x-www-form-urlencoded", nil
+	default:
+		return "", "", fmt.Errorf("unknown body encoding '%s'", encoding)
+	}
+}
+
+func applyAuth(req *http.Request, auth authSpec, body string) error {
+	switch strings.ToLower(auth.Type) {
+	case "", "none":
+		return nil
+	case "basic":
+		req.SetBasicAuth(auth.Username, auth.Password)
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case "hmac":
+		mac := hmac.New(sha256.New, []byte(auth.Secret))
+		mac.Write([]byte(body))
+		sig := hex.EncodeToString(mac.Sum(nil))
+		header := auth.Header
+		if header == "" {
+			header = "X-Signature"
+		}
+		req.Header.Set(header, sig)
+	default:
+		return fmt.Errorf("unknown auth type '%s'", auth.Type)
+	}
+	return nil
+}
+
+func extractExternalID(spec sendSpec, respBody string) (string, error) {
+	if len(spec.IDPath) > 0 {
+		id, err := jsonparser.GetString([]byte(respBody), spec.IDPath...)
+		if err != nil {
+			return "", errors.Wrap(err, "no external id returned in body")
+		}
+		return id, nil
+	}
+
+	if spec.IDRegex != "" {
+		re, err := regexp.Compile(spec.IDRegex)
+		if err != nil {
+			return "", errors.Wrap(err, "invalid id_regex")
+		}
+		match := re.FindStringSubmatch(respBody)
+		if len(match) < 2 {
+			return "", errors.Errorf("no external id found matching id_regex")
+		}
+		return match[1], nil
+	}
+
+	return "", errors.Errorf("no id_path or id_regex configured in request_spec")
+}
+
+// SendMsg sends the passed in message, returning any error
+func (h *handler) SendMsg(ctx context.Context, msg courier.Msg) (courier.MsgStatus, error) {
+	spec, err := h.getRequestSpec(msg.Channel())
+	if err != nil {
+		return nil, err
+	}
+
+	data := templateData{Msg: msg, Channel: msg.Channel(), Text: handlers.GetTextAndAttachments(msg)}
+
+	sendURL, err := renderTemplate("url", spec.Send.URL, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid url template")
+	}
+
+	method := spec.Send.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	body, contentType, err := buildBody(spec.Send, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid body template")
+	}
+
+	req, err := http.NewRequest(method, sendURL, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	for key, tpl := range spec.Send.Headers {
+		value, err := renderTemplate(key, tpl, data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid header template for '%s'", key)
+		}
+		req.Header.Set(key, value)
+	}
+
+	if err := applyAuth(req, spec.Send.Auth, body); err != nil {
+		return nil, err
+	}
+
+	rr, err := utils.MakeHTTPRequest(req)
+
+	// record our status and log
+	status := h.Backend().NewMsgStatusForID(msg.Channel(), msg.ID(), courier.MsgErrored)
+	status.AddLog(courier.NewChannelLogFromRR("Message Sent", msg.Channel(), msg.ID(), rr).WithError("Message Send Error", err))
+	// a non-2xx response also comes back as an error, but still carries a
+	// status code to classify below; only bail out here if none arrived
+	if err != nil && (rr == nil || rr.StatusCode == 0) {
+		return status, nil
+	}
+
+	if mapped, found := spec.Send.StatusCodes[fmt.Sprintf("%d", rr.StatusCode)]; found {
+		msgStatus, err := statusValueForName(mapped)
+		if err != nil {
+			return status, err
+		}
+		status.SetStatus(msgStatus)
+
+		// an explicitly mapped error status (e.g. "429": "errored") rarely
+		// carries the success payload's id field, so don't let a failed
+		// extraction there mask the already-correct mapped outcome.
+		if !isSuccessStatus(msgStatus) {
+			return status, nil
+		}
+	} else if rr.StatusCode/100 != 2 {
+		// a 4xx with no explicit status_codes mapping is a permanent
+		// failure (bad request/auth) and shouldn't be retried; anything
+		// else (5xx, 429) is left as MsgErrored for the engine to retry
+		if rr.StatusCode >= 400 && rr.StatusCode < 500 && rr.StatusCode != http.StatusTooManyRequests {
+			status.SetStatus(courier.MsgFailed)
+		}
+		return status, errors.Errorf("received non 200 status: %d", rr.StatusCode)
+	} else {
+		status.SetStatus(courier.MsgWired)
+	}
+
+	externalID, err := extractExternalID(spec.Send, rr.Body)
+	if err != nil {
+		return status, err
+	}
+	status.SetExternalID(externalID)
+
+	return status, nil
+}
+
+// isSuccessStatus reports whether a MsgStatusValue represents a message
+// that was actually accepted by the provider, as opposed to an errored
+// or failed outcome.
+func isSuccessStatus(status courier.MsgStatusValue) bool {
+	switch status {
+	case courier.MsgWired, courier.MsgSent, courier.MsgDelivered:
+		return true
+	default:
+		return false
+	}
+}
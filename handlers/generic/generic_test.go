@@ -0,0 +1,54 @@
+package generic
+
+import (
+	"testing"
+
+	"github.com/nyaruka/courier"
+)
+
+func TestExtractExternalID(t *testing.T) {
+	spec := sendSpec{IDPath: []string{"id"}}
+	id, err := extractExternalID(spec, `{"id":"external-123"}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if id != "external-123" {
+		t.Errorf("got id %q, want external-123", id)
+	}
+
+	if _, err := extractExternalID(spec, `{"status":"ok"}`); err == nil {
+		t.Errorf("expected error when id_path doesn't match the response")
+	}
+
+	regexSpec := sendSpec{IDRegex: `id=(\w+)`}
+	id, err = extractExternalID(regexSpec, "OK id=abc456")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if id != "abc456" {
+		t.Errorf("got id %q, want abc456", id)
+	}
+
+	if _, err := extractExternalID(sendSpec{}, `{"id":"123"}`); err == nil {
+		t.Errorf("expected error when neither id_path nor id_regex is configured")
+	}
+}
+
+func TestIsSuccessStatus(t *testing.T) {
+	cases := []struct {
+		status  courier.MsgStatusValue
+		success bool
+	}{
+		{courier.MsgWired, true},
+		{courier.MsgSent, true},
+		{courier.MsgDelivered, true},
+		{courier.MsgErrored, false},
+		{courier.MsgFailed, false},
+	}
+
+	for _, c := range cases {
+		if got := isSuccessStatus(c.status); got != c.success {
+			t.Errorf("isSuccessStatus(%s) = %v, want %v", c.status, got, c.success)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/nyaruka/courier"
+	"github.com/pkg/errors"
+)
+
+// Channel config keys used to opt a channel into signed webhooks.
+const (
+	configSignatureScheme = "signature_scheme"
+	configSignatureSecret = "signature_secret"
+	configSignatureSkew   = "signature_skew" // seconds, defaults to defaultSkew
+
+	schemeTwilio = "twilio"      // X-Twilio-Signature over URL + sorted POST params, base64 HMAC-SHA1
+	schemeHMAC   = "hmac-sha256" // X-Signature: sha256=hex(hmac(secret, timestamp + "." + body))
+	schemeJWT    = "jwt"         // Authorization: Bearer <HMAC signed JWT>
+)
+
+const defaultSkew = 5 * time.Minute
+
+// NonceStore records nonces that have already been used on a signed
+// webhook so a captured request can't be replayed. The default is a
+// small in-memory LRU; SetNonceStore lets a multi-instance deployment
+// swap in a shared, Redis-backed implementation.
+type NonceStore interface {
+	SeenRecently(nonce string) bool
+}
+
+type memoryNonceStore struct {
+	seen *lru.Cache
+}
+
+func (m *memoryNonceStore) SeenRecently(nonce string) bool {
+	if m.seen.Contains(nonce) {
+		return true
+	}
+	m.seen.Add(nonce, true)
+	return false
+}
+
+func newMemoryNonceStore() *memoryNonceStore {
+	cache, _ := lru.New(10000)
+	return &memoryNonceStore{seen: cache}
+}
+
+var globalNonceStore NonceStore = newMemoryNonceStore()
+
+// SetNonceStore overrides the default in-memory nonce store. Deployments
+// running multiple courier instances behind a load balancer should use
+// this to install a store shared across them.
+func SetNonceStore(s NonceStore) {
+	globalNonceStore = s
+}
+
+// VerifySignedWebhook checks an incoming receive/status request against
+// the signing scheme declared in the channel's config, returning an
+// error if the signature, timestamp or nonce don't check out. Every
+// scheme requires an X-Timestamp header within the configured skew
+// window and an unused X-Nonce header, so replay protection applies
+// uniformly regardless of which scheme a channel picks. Handlers call
+// this at the top of ReceiveMessage/StatusMessage, before
+// DecodeAndValidateForm runs, so a forged request never reaches handler
+// logic. Channels that don't set signature_scheme are left untouched.
+func VerifySignedWebhook(channel courier.Channel, r *http.Request) error {
+	scheme := channel.StringConfigForKey(configSignatureScheme, "")
+	if scheme == "" {
+		return nil
+	}
+
+	secret := channel.StringConfigForKey(configSignatureSecret, "")
+	if secret == "" {
+		return fmt.Errorf("channel configured for signed webhooks but has no %s", configSignatureSecret)
+	}
+
+	skew := defaultSkew
+	if raw := channel.StringConfigForKey(configSignatureSkew, ""); raw != "" {
+		if secs, err := strconv.Atoi(raw); err == nil {
+			skew = time.Duration(secs) * time.Second
+		}
+	}
+
+	if err := checkReplay(r, skew); err != nil {
+		return err
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return errors.Wrap(err, "error reading request body")
+	}
+	r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+
+	switch scheme {
+	case schemeTwilio:
+		return verifyTwilioSignature(r, secret)
+	case schemeHMAC:
+		return verifyHMACSignature(r, secret, body)
+	case schemeJWT:
+		return verifyJWTBearer(r, secret)
+	default:
+		return fmt.Errorf("unknown signature_scheme '%s'", scheme)
+	}
+}
+
+// checkReplay enforces the timestamp skew window and nonce reuse check
+// common to every signing scheme. Both X-Timestamp and X-Nonce are
+// mandatory: a missing nonce is rejected rather than silently skipped,
+// since otherwise a captured request could be replayed freely for the
+// whole skew window simply by dropping the header.
+func checkReplay(r *http.Request, skew time.Duration) error {
+	ts := r.Header.Get("X-Timestamp")
+	if ts == "" {
+		return fmt.Errorf("missing X-Timestamp header")
+	}
+
+	tsSecs, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid X-Timestamp header")
+	}
+	sentAt := time.Unix(tsSecs, 0)
+	if time.Since(sentAt) > skew || time.Until(sentAt) > skew {
+		return fmt.Errorf("request timestamp outside of allowed skew")
+	}
+
+	nonce := r.Header.Get("X-Nonce")
+	if nonce == "" {
+		return fmt.Errorf("missing X-Nonce header")
+	}
+	if globalNonceStore.SeenRecently(nonce) {
+		return fmt.Errorf("nonce has already been used")
+	}
+
+	return nil
+}
+
+func verifyTwilioSignature(r *http.Request, secret string) error {
+	sig := r.Header.Get("X-Twilio-Signature")
+	if sig == "" {
+		return fmt.Errorf("missing X-Twilio-Signature header")
+	}
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(r.PostForm))
+	for k := range r.PostForm {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	payload := r.URL.String()
+	for _, k := range keys {
+		payload += k + r.PostForm.Get(k)
+	}
+
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(payload))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func verifyHMACSignature(r *http.Request, secret string, body []byte) error {
+	ts := r.Header.Get("X-Timestamp")
+	sig := r.Header.Get("X-Signature")
+	if sig == "" {
+		return fmt.Errorf("missing X-Signature header")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "." + string(body)))
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	sig = strings.TrimPrefix(sig, "sha256=")
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func verifyJWTBearer(r *http.Request, secret string) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return fmt.Errorf("missing bearer token")
+	}
+
+	_, err := jwt.Parse(strings.TrimPrefix(auth, "Bearer "), func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "invalid bearer token")
+	}
+	return nil
+}
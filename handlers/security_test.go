@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTimestampedRequest(t *testing.T, method, target string, body string, ts time.Time, nonce string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	r.Header.Set("X-Timestamp", strconv.FormatInt(ts.Unix(), 10))
+	if nonce != "" {
+		r.Header.Set("X-Nonce", nonce)
+	}
+	return r
+}
+
+func TestCheckReplay(t *testing.T) {
+	now := time.Now()
+
+	r := newTimestampedRequest(t, http.MethodPost, "/receive", "", now, "abc123")
+	if err := checkReplay(r, defaultSkew); err != nil {
+		t.Fatalf("expected fresh timestamp+nonce to pass, got: %s", err)
+	}
+
+	// replaying the same nonce must be rejected
+	r2 := newTimestampedRequest(t, http.MethodPost, "/receive", "", now, "abc123")
+	if err := checkReplay(r2, defaultSkew); err == nil {
+		t.Fatalf("expected replayed nonce to be rejected")
+	}
+
+	// a missing nonce must be rejected outright, not silently allowed
+	r3 := newTimestampedRequest(t, http.MethodPost, "/receive", "", now, "")
+	if err := checkReplay(r3, defaultSkew); err == nil {
+		t.Fatalf("expected missing X-Nonce header to be rejected")
+	}
+
+	// a timestamp outside of the skew window must be rejected
+	r4 := newTimestampedRequest(t, http.MethodPost, "/receive", "", now.Add(-time.Hour), "def456")
+	if err := checkReplay(r4, defaultSkew); err == nil {
+		t.Fatalf("expected stale timestamp to be rejected")
+	}
+}
+
+func TestVerifyHMACSignature(t *testing.T) {
+	secret := "sekret"
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	body := []byte(`{"id":"123","status":"delivered"}`)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "." + string(body)))
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	r := httptest.NewRequest(http.MethodPost, "/status", nil)
+	r.Header.Set("X-Timestamp", ts)
+	r.Header.Set("X-Signature", "sha256="+sig)
+
+	if err := verifyHMACSignature(r, secret, body); err != nil {
+		t.Fatalf("expected valid signature to verify, got: %s", err)
+	}
+
+	r.Header.Set("X-Signature", "sha256=deadbeef")
+	if err := verifyHMACSignature(r, secret, body); err == nil {
+		t.Fatalf("expected forged signature to be rejected")
+	}
+}
+
+func TestVerifyTwilioSignature(t *testing.T) {
+	secret := "sekret"
+	form := url.Values{"To": []string{"+12065551212"}, "From": []string{"+12065551313"}, "Body": []string{"hi"}}
+
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/receive", nil)
+	r.PostForm = form
+
+	keys := []string{"Body", "From", "To"}
+	payload := r.URL.String()
+	for _, k := range keys {
+		payload += k + form.Get(k)
+	}
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(payload))
+	sig := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	r.Header.Set("X-Twilio-Signature", sig)
+
+	if err := verifyTwilioSignature(r, secret); err != nil {
+		t.Fatalf("expected valid signature to verify, got: %s", err)
+	}
+
+	r.Header.Set("X-Twilio-Signature", "bm9wZQ==")
+	if err := verifyTwilioSignature(r, secret); err == nil {
+		t.Fatalf("expected forged signature to be rejected")
+	}
+}
+
+func TestVerifyJWTBearer(t *testing.T) {
+	secret := "sekret"
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix()})
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("error signing test token: %s", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/receive", nil)
+	r.Header.Set("Authorization", "Bearer "+signed)
+	if err := verifyJWTBearer(r, secret); err != nil {
+		t.Fatalf("expected valid bearer token to verify, got: %s", err)
+	}
+
+	r.Header.Set("Authorization", "Bearer not-a-token")
+	if err := verifyJWTBearer(r, secret); err == nil {
+		t.Fatalf("expected forged bearer token to be rejected")
+	}
+
+	r.Header.Del("Authorization")
+	if err := verifyJWTBearer(r, secret); err == nil {
+		t.Fatalf("expected missing bearer token to be rejected")
+	}
+}
@@ -0,0 +1,125 @@
+package courier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/cenkalti/backoff"
+)
+
+// RetryPolicy configures the exponential backoff applied around a
+// handler's SendMsg call.
+type RetryPolicy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxElapsedTime  time.Duration
+}
+
+// DefaultRetryPolicy is used for channel types that don't configure
+// their own policy.
+var DefaultRetryPolicy = RetryPolicy{
+	InitialInterval: 500 * time.Millisecond,
+	MaxInterval:     30 * time.Second,
+	MaxElapsedTime:  2 * time.Minute,
+}
+
+func (p RetryPolicy) newBackOff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = p.InitialInterval
+	b.MaxInterval = p.MaxInterval
+	b.MaxElapsedTime = p.MaxElapsedTime
+	return b
+}
+
+// errRetryableStatus signals to the backoff loop that the handler
+// returned a status we should keep retrying, without treating it as a
+// fatal error to surface to the caller.
+var errRetryableStatus = errors.New("message was not successfully dispatched")
+
+// shouldRetryStatus reports whether a MsgStatus returned by a handler's
+// SendMsg should be retried. Only MsgErrored is retryable; MsgFailed and
+// every successful status are left alone.
+func shouldRetryStatus(status MsgStatusValue) bool {
+	return status == MsgErrored
+}
+
+// sendMsgWithRetry wraps a handler's SendMsg call with exponential
+// backoff, retrying on network errors and on a MsgErrored status
+// (handlers return MsgErrored for retryable failures such as timeouts or
+// 5xx/429 responses, and MsgFailed for permanent ones like a 4xx) but
+// never retrying a MsgFailed status. Every attempt, successful or not,
+// is appended to the returned MsgStatus if it implements DispatchLogger.
+func sendMsgWithRetry(ctx context.Context, h ChannelHandler, msg Msg, policy RetryPolicy) (MsgStatus, error) {
+	var status MsgStatus
+	var sendErr error
+	var attempts []DispatchAttempt
+	attempt := 0
+
+	op := func() error {
+		attempt++
+		started := time.Now()
+		status, sendErr = h.SendMsg(ctx, msg)
+		finished := time.Now()
+
+		record := DispatchAttempt{Attempt: attempt, StartedOn: started, FinishedOn: finished}
+		if sendErr != nil {
+			record.Error = sendErr.Error()
+		}
+		if status != nil {
+			record.Status = status.Status()
+		}
+		attempts = append(attempts, record)
+
+		// Once a request has been made, handlers build a MsgStatus before
+		// returning even on failure (see blackmyna's SendMsg), and use MsgErrored for
+		// retryable failures (timeouts, 5xx, 429) vs MsgFailed for
+		// permanent ones (4xx) regardless of whether they also returned a
+		// Go error alongside it. So classify on the status first, and
+		// only fall back to inspecting the bare error when a handler
+		// couldn't produce a status at all.
+		if status != nil {
+			if shouldRetryStatus(status.Status()) {
+				return errRetryableStatus
+			}
+			return nil
+		}
+
+		if sendErr != nil {
+			if _, ok := sendErr.(net.Error); ok {
+				return sendErr
+			}
+			return backoff.Permanent(sendErr)
+		}
+		return nil
+	}
+
+	err := backoff.Retry(op, backoff.WithContext(policy.newBackOff(), ctx))
+
+	// each SendMsg call builds a fresh MsgStatus, so the whole attempt
+	// history goes onto the one from the last attempt that we return
+	if logger, ok := status.(DispatchLogger); ok {
+		for _, a := range attempts {
+			logger.AddDispatchAttempt(a)
+		}
+	}
+
+	if err != nil && err != errRetryableStatus {
+		return status, err
+	}
+
+	// exhausted our retries without ever seeing a non-errored status
+	if status != nil && status.Status() == MsgErrored {
+		status.SetStatus(MsgFailed)
+	}
+	return status, nil
+}
+
+// Dispatch is the integration point for sending a message with retry:
+// the engine's send worker calls this in place of calling
+// h.SendMsg(ctx, msg) directly, so every channel type gets backoff and
+// per-attempt dispatch logging without changing its SendMsg.
+func Dispatch(ctx context.Context, h ChannelHandler, msg Msg, policy RetryPolicy) (MsgStatus, error) {
+	return sendMsgWithRetry(ctx, h, msg, policy)
+}
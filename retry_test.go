@@ -0,0 +1,22 @@
+package courier
+
+import "testing"
+
+func TestShouldRetryStatus(t *testing.T) {
+	cases := []struct {
+		status MsgStatusValue
+		retry  bool
+	}{
+		{MsgErrored, true},
+		{MsgFailed, false},
+		{MsgWired, false},
+		{MsgSent, false},
+		{MsgDelivered, false},
+	}
+
+	for _, c := range cases {
+		if got := shouldRetryStatus(c.status); got != c.retry {
+			t.Errorf("shouldRetryStatus(%s) = %v, want %v", c.status, got, c.retry)
+		}
+	}
+}